@@ -0,0 +1,235 @@
+package fix_latin
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// Charmap maps the 256 possible bytes of a single-byte encoding to their
+// UTF-8 representation, analogous to golang.org/x/text/encoding/charmap.Charmap.
+// A byte with no entry is left undefined: decoding it fails. Charmap
+// implements encoding.Encoding, so it composes with the rest of the
+// x/text ecosystem (encoding.ReplaceUnsupported, htmlindex, ...) as well
+// as with Fixer.
+type Charmap struct {
+	Name          string
+	MIB           int
+	ASCIISuperset bool
+
+	table    [256][]byte
+	identity [256]bool // table[b] is b's bare Latin-1 code point, not an override
+	reverse  map[string]byte
+}
+
+// NewCharmap builds a Charmap that maps every byte to its own Latin-1 code
+// point, except for the bytes listed in overrides (decoded to the given
+// UTF-8 bytes instead) and undefined (left without a mapping).
+func NewCharmap(name string, mib int, overrides map[byte][]byte, undefined map[byte]bool) *Charmap {
+	cm := &Charmap{Name: name, MIB: mib, ASCIISuperset: true, reverse: map[string]byte{}}
+	for b := 0; b < 256; b++ {
+		bb := byte(b)
+		switch {
+		case undefined[bb]:
+		case overrides[bb] != nil:
+			cm.table[bb] = overrides[bb]
+		default:
+			cm.table[bb] = []byte(string(rune(bb)))
+			cm.identity[bb] = true
+		}
+		if cm.table[bb] != nil {
+			cm.reverse[string(cm.table[bb])] = bb
+		}
+	}
+	return cm
+}
+
+// Decode returns the UTF-8 representation of b under cm, and whether cm
+// defines b at all.
+func (cm *Charmap) Decode(b byte) ([]byte, bool) {
+	repl := cm.table[b]
+	return repl, repl != nil
+}
+
+// isRawControl reports whether b decodes under cm to its own bare Latin-1
+// control code point rather than to some character cm assigns it.
+func (cm *Charmap) isRawControl(b byte) bool {
+	return cm.identity[b]
+}
+
+// NewDecoder returns a transform.Transformer that decodes cm's single-byte
+// encoding into UTF-8, satisfying encoding.Encoding.
+func (cm *Charmap) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: &charmapDecoder{cm: cm}}
+}
+
+// NewEncoder returns a transform.Transformer that encodes UTF-8 into cm's
+// single-byte encoding, satisfying encoding.Encoding.
+func (cm *Charmap) NewEncoder() *encoding.Encoder {
+	return &encoding.Encoder{Transformer: &charmapEncoder{cm: cm}}
+}
+
+// EncodeError reports a rune that a Charmap's encoder can't represent.
+type EncodeError struct {
+	Offset int64
+	Rune   rune
+}
+
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("fix_latin: rune %q not representable at offset %d", e.Rune, e.Offset)
+}
+
+// charmapDecoder is the transform.Transformer behind Charmap.NewDecoder.
+type charmapDecoder struct {
+	cm *Charmap
+}
+
+func (d *charmapDecoder) Reset() {}
+
+func (d *charmapDecoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		b := src[nSrc]
+		repl, ok := d.cm.Decode(b)
+		if !ok {
+			return nDst, nSrc, &DecodeError{Offset: int64(nSrc), Byte: b, Reason: "undecodable byte"}
+		}
+		if nDst+len(repl) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		copy(dst[nDst:], repl)
+		nDst += len(repl)
+		nSrc++
+	}
+	return nDst, nSrc, nil
+}
+
+// charmapEncoder is the transform.Transformer behind Charmap.NewEncoder.
+type charmapEncoder struct {
+	cm *Charmap
+}
+
+func (e *charmapEncoder) Reset() {}
+
+func (e *charmapEncoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			return nDst, nSrc, &EncodeError{Offset: int64(nSrc), Rune: r}
+		}
+		b, ok := e.cm.reverse[string(src[nSrc:nSrc+size])]
+		if !ok {
+			return nDst, nSrc, &EncodeError{Offset: int64(nSrc), Rune: r}
+		}
+		if nDst >= len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		dst[nDst] = b
+		nDst++
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+var charmaps = map[string]*Charmap{}
+
+// RegisterCharmap makes cm available for lookup by name, e.g. from the
+// command-line --encoding flag.
+func RegisterCharmap(name string, cm *Charmap) {
+	charmaps[name] = cm
+}
+
+// LookupCharmap returns the Charmap previously passed to RegisterCharmap
+// under name.
+func LookupCharmap(name string) (*Charmap, bool) {
+	cm, ok := charmaps[name]
+	return cm, ok
+}
+
+// remainder is ISO-8859-1
+var iso885915Overrides = map[byte][]byte{
+	0xA4: {0xE2, 0x82, 0xAC}, // EURO SIGN
+	0xA6: {0xC5, 0xA0},       // LATIN CAPITAL LETTER S WITH CARON
+	0xA8: {0xC5, 0xA1},       // LATIN SMALL LETTER S WITH CARON
+	0xB4: {0xC5, 0xBD},       // LATIN CAPITAL LETTER Z WITH CARON
+	0xB8: {0xC5, 0xBE},       // LATIN SMALL LETTER Z WITH CARON
+	0xBC: {0xC5, 0x92},       // LATIN CAPITAL LIGATURE OE
+	0xBD: {0xC5, 0x93},       // LATIN SMALL LIGATURE OE
+	0xBE: {0xC5, 0xB8},       // LATIN CAPITAL LETTER Y WITH DIAERESIS
+}
+
+// remainder is ISO-8859-1
+// does not define 0x81, 0x8D, 0x8F, 0x90, 0x9D
+var cp1252Overrides = map[byte][]byte{
+	0x80: {0xE2, 0x82, 0xAC}, // EURO SIGN
+	0x82: {0xE2, 0x80, 0x9A}, // SINGLE LOW-9 QUOTATION MARK
+	0x83: {0xC6, 0x92},       // LATIN SMALL LETTER F WITH HOOK
+	0x84: {0xE2, 0x80, 0x9E}, // DOUBLE LOW-9 QUOTATION MARK
+	0x85: {0xE2, 0x80, 0xA6}, // HORIZONTAL ELLIPSIS
+	0x86: {0xE2, 0x80, 0xA0}, // DAGGER
+	0x87: {0xE2, 0x80, 0xA1}, // DOUBLE DAGGER
+	0x88: {0xCB, 0x86},       // MODIFIER LETTER CIRCUMFLEX ACCENT
+	0x89: {0xE2, 0x80, 0xB0}, // PER MILLE SIGN
+	0x8A: {0xC5, 0xA0},       // LATIN CAPITAL LETTER S WITH CARON
+	0x8B: {0xE2, 0x80, 0xB9}, // SINGLE LEFT-POINTING ANGLE QUOTATION MARK
+	0x8C: {0xC5, 0x92},       // LATIN CAPITAL LIGATURE OE
+	0x8E: {0xC5, 0xBD},       // LATIN CAPITAL LETTER Z WITH CARON
+	0x91: {0xE2, 0x80, 0x98}, // LEFT SINGLE QUOTATION MARK
+	0x92: {0xE2, 0x80, 0x99}, // RIGHT SINGLE QUOTATION MARK
+	0x93: {0xE2, 0x80, 0x9C}, // LEFT DOUBLE QUOTATION MARK
+	0x94: {0xE2, 0x80, 0x9D}, // RIGHT DOUBLE QUOTATION MARK
+	0x95: {0xE2, 0x80, 0xA2}, // BULLET
+	0x96: {0xE2, 0x80, 0x93}, // EN DASH
+	0x97: {0xE2, 0x80, 0x94}, // EM DASH
+	0x98: {0xCB, 0x9C},       // SMALL TILDE
+	0x99: {0xE2, 0x84, 0xA2}, // TRADE MARK SIGN
+	0x9A: {0xC5, 0xA1},       // LATIN SMALL LETTER S WITH CARON
+	0x9B: {0xE2, 0x80, 0xBA}, // SINGLE RIGHT-POINTING ANGLE QUOTATION MARK
+	0x9C: {0xC5, 0x93},       // LATIN SMALL LIGATURE OE
+	0x9E: {0xC5, 0xBE},       // LATIN SMALL LETTER Z WITH CARON
+	0x9F: {0xC5, 0xB8},       // LATIN CAPITAL LETTER Y WITH DIAERESIS
+}
+
+var cp1252Undefined = map[byte]bool{
+	0x81: true, 0x8D: true, 0x8F: true, 0x90: true, 0x9D: true,
+}
+
+var (
+	// ISO_8859_1 is the Western European Latin-1 charmap: every byte
+	// decodes to its own code point.
+	ISO_8859_1 = NewCharmap("ISO-8859-1", 4, nil, nil)
+
+	// ISO_8859_15 is Latin-1 with the Euro sign, the S/Z caron letters
+	// and the OE/Y-diaeresis letters swapped in over some symbol slots.
+	ISO_8859_15 = NewCharmap("ISO-8859-15", 111, iso885915Overrides, nil)
+
+	// CP1252 is Microsoft's Windows-1252, Latin-1 with the 0x80-0x9F
+	// control range replaced by printable punctuation and letters, except
+	// for the 5 positions (0x81, 0x8D, 0x8F, 0x90, 0x9D) Windows-1252
+	// leaves unassigned; those are left as identity (raw Latin-1 control
+	// code points), not passed as undefined, so they're gated by
+	// AllowControl exactly like a plain ISO_8859_1 control byte rather
+	// than always failing to decode.
+	CP1252 = NewCharmap("windows-1252", 2252, cp1252Overrides, nil)
+
+	// UTF8 is what Detect reports for input whose inspected prefix is
+	// valid UTF-8, or which starts with a UTF-8 or UTF-16 byte order mark
+	// (the rest of such input is not validated); it defines no bytes of
+	// its own, so any byte a Fixer assumes it for fails to decode instead
+	// of being silently reinterpreted.
+	UTF8 = &Charmap{Name: "UTF-8", MIB: 106, ASCIISuperset: true}
+
+	// Auto defers the choice of Charmap to Detect, run against the input
+	// at Fix time. It isn't meaningful to pass to a Fixer driven by hand.
+	Auto = &Charmap{Name: "auto"}
+)
+
+func init() {
+	RegisterCharmap(ISO_8859_1.Name, ISO_8859_1)
+	RegisterCharmap(ISO_8859_15.Name, ISO_8859_15)
+	RegisterCharmap(CP1252.Name, CP1252)
+}
@@ -0,0 +1,104 @@
+package fix_latin
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// This asserts at compile time that *Charmap satisfies
+// encoding.Encoding, so it composes with the rest of the x/text
+// ecosystem rather than only with Fixer.
+var _ encoding.Encoding = CP1252
+
+func TestCharmapDecode(t *testing.T) {
+	cases := []struct {
+		name    string
+		cm      *Charmap
+		b       byte
+		wantOK  bool
+		wantStr string
+	}{
+		{"ISO-8859-1 ASCII", ISO_8859_1, 'A', true, "A"},
+		{"ISO-8859-1 high byte", ISO_8859_1, 0xE9, true, "é"}, // é
+		{"CP1252 override (curly quote)", CP1252, 0x93, true, "“"},
+		{"CP1252 euro sign", CP1252, 0x80, true, "€"},
+		{"CP1252 undefined byte decodes as raw Latin-1", CP1252, 0x81, true, string(rune(0x81))},
+		{"ISO-8859-15 euro sign", ISO_8859_15, 0xA4, true, "€"},
+		{"ISO-8859-15 untouched byte", ISO_8859_15, 0xE9, true, "é"},
+		{"UTF8 charmap defines nothing", UTF8, 0x80, false, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := c.cm.Decode(c.b)
+			if ok != c.wantOK {
+				t.Fatalf("Decode(0x%02X) ok = %v, want %v", c.b, ok, c.wantOK)
+			}
+			if ok && string(got) != c.wantStr {
+				t.Errorf("Decode(0x%02X) = %q, want %q", c.b, got, c.wantStr)
+			}
+		})
+	}
+}
+
+// TestFixCP1252UndefinedByteUnderAllowControl guards the regression where
+// CP1252's 5 unassigned C1 control positions (0x81, 0x8D, 0x8F, 0x90,
+// 0x9D) always failed to decode, even with AllowControl set, instead of
+// falling back to their raw Latin-1 control code point the way the
+// baseline tool (and a plain ISO_8859_1 byte) does.
+func TestFixCP1252UndefinedByteUnderAllowControl(t *testing.T) {
+	input := []byte("caf\x81e")
+
+	var strict bytes.Buffer
+	if _, err := Fix(bytes.NewReader(input), &strict, Assume(CP1252)); err == nil {
+		t.Fatalf("Fix without AllowControl: expected a DecodeError for 0x81, got none (wrote %q)", strict.String())
+	}
+
+	var allowed bytes.Buffer
+	n, err := Fix(bytes.NewReader(input), &allowed, AllowControl, Assume(CP1252))
+	if err != nil {
+		t.Fatalf("Fix with AllowControl: unexpected error: %v", err)
+	}
+	want := "caf" + string(rune(0x81)) + "e" // 0x81 falls back to its raw Latin-1 control code point
+	if got := allowed.String(); got != want {
+		t.Errorf("Fix with AllowControl = %q, want %q", got, want)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("Fix reported %d bytes written, want %d", n, len(want))
+	}
+}
+
+// TestCharmapEncodingRoundTrip drives Charmap's NewDecoder/NewEncoder
+// directly, the way a caller composing with the rest of x/text (rather
+// than going through Fixer) would.
+func TestCharmapEncodingRoundTrip(t *testing.T) {
+	original := []byte{'c', 'a', 'f', 0xE9} // "caf" + CP1252/Latin-1 é
+
+	decoded, err := transform.Bytes(CP1252.NewDecoder(), original)
+	if err != nil {
+		t.Fatalf("NewDecoder: unexpected error: %v", err)
+	}
+	if want := "café"; string(decoded) != want {
+		t.Fatalf("NewDecoder(%v) = %q, want %q", original, decoded, want)
+	}
+
+	encoded, err := transform.Bytes(CP1252.NewEncoder(), decoded)
+	if err != nil {
+		t.Fatalf("NewEncoder: unexpected error: %v", err)
+	}
+	if !bytes.Equal(encoded, original) {
+		t.Errorf("NewEncoder(%q) = %v, want %v", decoded, encoded, original)
+	}
+}
+
+// TestCharmapDecoderRejectsUndefinedByte checks that a Charmap's decoder,
+// used directly rather than through Fixer, surfaces a DecodeError for a
+// byte it can't map instead of silently dropping or mangling it.
+func TestCharmapDecoderRejectsUndefinedByte(t *testing.T) {
+	_, err := transform.Bytes(UTF8.NewDecoder(), []byte{0x80})
+	if err == nil {
+		t.Fatal("NewDecoder: expected an error for an undefined byte, got none")
+	}
+}
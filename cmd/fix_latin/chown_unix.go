@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// chownLike gives tmpPath the same uid/gid as info, where the platform's
+// os.FileInfo.Sys() exposes one.
+func chownLike(tmpPath string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return os.Chown(tmpPath, int(stat.Uid), int(stat.Gid))
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// chownLike is a no-op on Windows, which has no POSIX uid/gid to preserve.
+func chownLike(tmpPath string, info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,258 @@
+// Command fix_latin fixes mis-encoded text, either by rewriting files
+// in place or by filtering stdin to stdout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/text/transform"
+
+	"github.com/lloeki/fix_latin"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "fix":
+		err = runFix(os.Args[2:])
+	case "detect":
+		err = runDetect(os.Args[2:])
+	case "-h", "--help", "help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fix_latin:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  fix_latin fix [-i] [--encoding auto|cp1252|iso-8859-1|iso-8859-15] [--errors strict|replace|skip|escape] FILE...
+  fix_latin detect FILE...
+A FILE of "-" means stdin (and, for fix without -i, stdout).`)
+}
+
+func runFix(args []string) error {
+	fs := flag.NewFlagSet("fix", flag.ExitOnError)
+	inPlace := fs.Bool("i", false, "fix files in place instead of writing to stdout")
+	fs.BoolVar(inPlace, "in-place", false, "alias for -i")
+	encoding := fs.String("encoding", "cp1252", "assumed source encoding: auto, cp1252, iso-8859-1, iso-8859-15")
+	errors := fs.String("errors", "strict", "error policy: strict, replace, skip, escape")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cm, err := charmapFlag(*encoding)
+	if err != nil {
+		return err
+	}
+	policy, err := errorPolicyFlag(*errors)
+	if err != nil {
+		return err
+	}
+
+	files := expandGlobs(fs.Args())
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	for _, path := range files {
+		if err := fixFile(path, *inPlace, cm, policy); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func fixFile(path string, inPlace bool, cm *fix_latin.Charmap, policy fix_latin.ErrorPolicy) error {
+	var in io.Reader
+	var info os.FileInfo
+	if path == "-" {
+		in = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+		if info, err = f.Stat(); err != nil {
+			return err
+		}
+	}
+	counted := &countingReader{r: in}
+	in = counted
+
+	fixer, err := fix_latin.NewFixer(fix_latin.AllowControl, fix_latin.Assume(cm), fix_latin.OnError(policy))
+	if err != nil {
+		return err
+	}
+
+	if cm == fix_latin.Auto {
+		result, rest, err := fix_latin.Detect(in)
+		if err != nil {
+			return err
+		}
+		in = rest
+		fixer, err = fix_latin.NewFixer(fix_latin.AllowControl, fix_latin.Assume(result.Charmap), fix_latin.OnError(policy))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: detected %s (confidence %.2f)\n", path, result.Charmap.Name, result.Confidence)
+	}
+
+	var out io.Writer
+	var tmpPath string
+	if !inPlace || path == "-" {
+		out = os.Stdout
+	} else {
+		tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".fix_latin-*")
+		if err != nil {
+			return err
+		}
+		defer tmp.Close()
+		tmpPath = tmp.Name()
+		out = tmp
+	}
+
+	written, err := io.Copy(out, transform.NewReader(in, fixer))
+	if err != nil {
+		if tmpPath != "" {
+			os.Remove(tmpPath)
+		}
+		return err
+	}
+
+	if tmpPath != "" {
+		if err := finishInPlace(tmpPath, path, info); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: read %d bytes, wrote %d bytes, %d replacements\n",
+		path, counted.n, written, fixer.Replacements())
+	return nil
+}
+
+// countingReader tracks how many bytes have been read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// finishInPlace gives tmpPath the original file's mode and ownership and
+// atomically renames it over path. Preserving the exact uid/gid is a
+// best-effort nicety: a chownLike failure (the process isn't the file's
+// owner, a setgid directory assigned the temp file a different group,
+// ...) is reported but doesn't discard an already-successful fix.
+func finishInPlace(tmpPath, path string, info os.FileInfo) error {
+	if err := os.Chmod(tmpPath, info.Mode()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := chownLike(tmpPath, info); err != nil {
+		fmt.Fprintf(os.Stderr, "fix_latin: %s: keeping fixed contents despite failing to preserve ownership: %v\n", path, err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func runDetect(args []string) error {
+	files := expandGlobs(args)
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, path := range files {
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		result, _, err := fix_latin.Detect(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("%s: %s (confidence %.2f)\n", path, result.Charmap.Name, result.Confidence)
+	}
+	return nil
+}
+
+func charmapFlag(name string) (*fix_latin.Charmap, error) {
+	switch name {
+	case "auto":
+		return fix_latin.Auto, nil
+	case "cp1252":
+		return fix_latin.CP1252, nil
+	case "iso-8859-1":
+		return fix_latin.ISO_8859_1, nil
+	case "iso-8859-15":
+		return fix_latin.ISO_8859_15, nil
+	}
+	if cm, ok := fix_latin.LookupCharmap(name); ok {
+		return cm, nil
+	}
+	return nil, fmt.Errorf("unknown --encoding %q", name)
+}
+
+func errorPolicyFlag(name string) (fix_latin.ErrorPolicy, error) {
+	switch name {
+	case "strict":
+		return fix_latin.Strict, nil
+	case "replace":
+		return fix_latin.Replace('�'), nil
+	case "skip":
+		return fix_latin.Skip, nil
+	case "escape":
+		return fix_latin.Escape, nil
+	}
+	return fix_latin.ErrorPolicy{}, fmt.Errorf("unknown --errors %q", name)
+}
+
+// expandGlobs expands shell globs on platforms (namely Windows) whose
+// shell doesn't already do it; elsewhere args arrive pre-expanded and
+// are returned unchanged.
+func expandGlobs(args []string) []string {
+	if runtime.GOOS != "windows" {
+		return args
+	}
+	var out []string
+	for _, arg := range args {
+		if arg == "-" {
+			out = append(out, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil || len(matches) == 0 {
+			out = append(out, arg)
+			continue
+		}
+		out = append(out, matches...)
+	}
+	return out
+}
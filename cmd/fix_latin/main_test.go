@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFinishInPlace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orig.txt")
+	if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+		t.Fatalf("seeding original file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat original file: %v", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatalf("chmod original file: %v", err)
+	}
+	info, err = os.Stat(path)
+	if err != nil {
+		t.Fatalf("re-stat original file: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".fix_latin-*")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.WriteString("fixed"); err != nil {
+		t.Fatalf("writing temp file: %v", err)
+	}
+	tmp.Close()
+
+	if err := finishInPlace(tmpPath, path, info); err != nil {
+		t.Fatalf("finishInPlace: %v", err)
+	}
+
+	if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Errorf("temp file %s still exists after finishInPlace", tmpPath)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading renamed file: %v", err)
+	}
+	if string(got) != "fixed" {
+		t.Errorf("path contains %q after finishInPlace, want %q", got, "fixed")
+	}
+
+	gotInfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat renamed file: %v", err)
+	}
+	if gotInfo.Mode() != info.Mode() {
+		t.Errorf("finishInPlace left mode %v, want %v", gotInfo.Mode(), info.Mode())
+	}
+}
@@ -0,0 +1,153 @@
+package fix_latin
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// detectPeekSize is how much of the input Detect looks at before making a
+// decision.
+const detectPeekSize = 4096
+
+// DetectResult is the outcome of Detect: the Charmap it picked, and how
+// confident it is in that pick.
+type DetectResult struct {
+	Charmap    *Charmap
+	Confidence float64
+}
+
+// cp1252Weight scores bytes that are distinctive of CP1252's Windows-1252
+// extensions over the 0x80-0x9F range that Latin-1 leaves as controls.
+var cp1252Weight = map[byte]int{
+	0x80: 3, // EURO SIGN
+	0x85: 2, // HORIZONTAL ELLIPSIS
+	0x91: 3, // LEFT SINGLE QUOTATION MARK
+	0x92: 3, // RIGHT SINGLE QUOTATION MARK
+	0x93: 3, // LEFT DOUBLE QUOTATION MARK
+	0x94: 3, // RIGHT DOUBLE QUOTATION MARK
+	0x96: 2, // EN DASH
+	0x97: 2, // EM DASH
+}
+
+// iso885915Weight scores bytes that only make sense as ISO-8859-15's
+// additions over plain ISO-8859-1 (the Euro sign and the OE ligatures).
+var iso885915Weight = map[byte]int{
+	0xA4: 3, // EURO SIGN
+	0xBC: 2, // LATIN CAPITAL LIGATURE OE
+	0xBD: 2, // LATIN SMALL LIGATURE OE
+}
+
+// Detect inspects a prefix of r to guess whether it is UTF-8, ISO-8859-1,
+// ISO-8859-15 or CP1252, and returns an io.Reader that replays the
+// consumed prefix followed by the rest of r.
+func Detect(r io.Reader) (DetectResult, io.Reader, error) {
+	br := bufio.NewReaderSize(r, detectPeekSize)
+	peek, err := br.Peek(detectPeekSize)
+	if err != nil && err != io.EOF {
+		return DetectResult{}, br, err
+	}
+
+	utf8Check := peek
+	if err == nil {
+		// A full peek means r may hold more bytes past the boundary, so
+		// peek can end mid-sequence; trim the possibly-truncated tail
+		// before judging validity, or a valid file gets misdetected purely
+		// because of where detectPeekSize happened to fall.
+		utf8Check = trimIncompleteUTF8Suffix(peek)
+	}
+
+	if hasUTF8BOM(peek) || hasUTF16BOM(peek) || looksLikeUTF8(utf8Check) {
+		return DetectResult{Charmap: UTF8, Confidence: 1}, br, nil
+	}
+
+	scores := map[*Charmap]int{ISO_8859_1: 0, ISO_8859_15: 0, CP1252: 0}
+	for _, b := range peek {
+		if b < 0x80 {
+			continue
+		}
+		switch {
+		case b >= 0x80 && b <= 0x9F:
+			if cp1252Undefined[b] {
+				scores[CP1252] -= 10
+			} else {
+				scores[CP1252] += cp1252Weight[b]
+			}
+			scores[ISO_8859_1] -= 3
+			scores[ISO_8859_15] -= 3
+		default:
+			scores[ISO_8859_1]++
+			scores[ISO_8859_15] += 1 + iso885915Weight[b]
+		}
+	}
+
+	best, bestScore := ISO_8859_1, scores[ISO_8859_1]
+	for _, cm := range []*Charmap{ISO_8859_15, CP1252} {
+		if scores[cm] > bestScore {
+			best, bestScore = cm, scores[cm]
+		}
+	}
+
+	total := 1
+	for _, s := range scores {
+		if s > 0 {
+			total += s
+		}
+	}
+	confidence := float64(bestScore) / float64(total)
+	if confidence < 0 {
+		confidence = 0
+	}
+
+	return DetectResult{Charmap: best, Confidence: confidence}, br, nil
+}
+
+func hasUTF8BOM(b []byte) bool {
+	return len(b) >= 3 && b[0] == 0xEF && b[1] == 0xBB && b[2] == 0xBF
+}
+
+func hasUTF16BOM(b []byte) bool {
+	return len(b) >= 2 && ((b[0] == 0xFE && b[1] == 0xFF) || (b[0] == 0xFF && b[1] == 0xFE))
+}
+
+// trimIncompleteUTF8Suffix drops a trailing lead byte (and whatever
+// continuation bytes it already has) from the end of b if it doesn't have
+// enough bytes left to complete its sequence, so a prefix cut off mid
+// character doesn't look like invalid UTF-8 to looksLikeUTF8.
+func trimIncompleteUTF8Suffix(b []byte) []byte {
+	lim := len(b) - 4
+	if lim < 0 {
+		lim = 0
+	}
+	for i := len(b) - 1; i >= lim; i-- {
+		c := b[i]
+		if c < 0x80 {
+			// ASCII byte: nothing multibyte is in progress.
+			break
+		}
+		if c >= 0xC0 {
+			if i+utf8LeadLen(c) > len(b) {
+				return b[:i]
+			}
+			break
+		}
+		// c is a continuation byte (0x80-0xBF): keep walking back to find
+		// the lead byte it belongs to.
+	}
+	return b
+}
+
+// looksLikeUTF8 reports whether b is strictly valid UTF-8 and contains at
+// least one non-ASCII character, which rules out mistaking plain ASCII
+// for a positive UTF-8 detection.
+func looksLikeUTF8(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
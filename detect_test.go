@@ -0,0 +1,78 @@
+package fix_latin
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDetectBOMs(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []byte
+	}{
+		{"UTF-8 BOM", []byte{0xEF, 0xBB, 0xBF, 'h', 'i'}},
+		{"UTF-16LE BOM", []byte{0xFF, 0xFE, 'h', 0}},
+		{"UTF-16BE BOM", []byte{0xFE, 0xFF, 0, 'h'}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, _, err := Detect(bytes.NewReader(c.in))
+			if err != nil {
+				t.Fatalf("Detect: unexpected error: %v", err)
+			}
+			if result.Charmap != UTF8 || result.Confidence != 1 {
+				t.Errorf("Detect(%v) = %+v, want {UTF8 1}", c.in, result)
+			}
+		})
+	}
+}
+
+func TestDetectScoring(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []byte
+		want *Charmap
+	}{
+		{"plain ASCII", []byte("hello, world"), ISO_8859_1},
+		{"CP1252 curly quotes", bytes.Repeat([]byte{0x93, 'x', 0x94}, 10), CP1252},
+		{"ISO-8859-15 euro and OE ligature", bytes.Repeat([]byte{0xA4, 'x', 0xBC}, 10), ISO_8859_15},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, _, err := Detect(bytes.NewReader(c.in))
+			if err != nil {
+				t.Fatalf("Detect: unexpected error: %v", err)
+			}
+			if result.Charmap != c.want {
+				t.Errorf("Detect(%q) picked %s, want %s", c.in, result.Charmap.Name, c.want.Name)
+			}
+		})
+	}
+}
+
+// TestDetectUTF8StraddlingPeekBoundary guards against the regression where
+// a multibyte sequence landing right at the detectPeekSize boundary got
+// truncated by Peek, making looksLikeUTF8 see an incomplete (and thus
+// "invalid") sequence in otherwise fully well-formed UTF-8.
+func TestDetectUTF8StraddlingPeekBoundary(t *testing.T) {
+	padding := strings.Repeat("a", detectPeekSize-5)
+	in := "€" + padding + "€€€" + strings.Repeat("b", 1024)
+
+	result, rest, err := Detect(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Detect: unexpected error: %v", err)
+	}
+	if result.Charmap != UTF8 || result.Confidence != 1 {
+		t.Fatalf("Detect = %+v, want {UTF8 1}", result)
+	}
+
+	got, err := io.ReadAll(rest)
+	if err != nil {
+		t.Fatalf("reading replayed reader: %v", err)
+	}
+	if string(got) != in {
+		t.Errorf("Detect's replayed reader did not reproduce the input (got %d bytes, want %d)", len(got), len(in))
+	}
+}
@@ -0,0 +1,95 @@
+package fix_latin
+
+import "fmt"
+
+// DecodeError reports a byte (or, for an overlong UTF-8 sequence, its
+// lead byte) that a Fixer under the Strict ErrorPolicy refused to decode.
+type DecodeError struct {
+	Offset int64
+	Byte   byte
+	Reason string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("fix_latin: invalid byte 0x%02X at offset %d: %s", e.Byte, e.Offset, e.Reason)
+}
+
+// ErrorPolicy decides what a Fixer does with a byte it cannot decode,
+// modeled on Python codecs' errors= argument: stop, substitute, drop, or
+// escape.
+type ErrorPolicy struct {
+	name  string
+	apply func(offset int64, b byte, reason string) (repl []byte, err error)
+}
+
+// Strict is the default ErrorPolicy: it reports undecodable bytes as a
+// *DecodeError and stops the Fixer.
+var Strict = ErrorPolicy{
+	name: "strict",
+	apply: func(offset int64, b byte, reason string) ([]byte, error) {
+		return nil, &DecodeError{Offset: offset, Byte: b, Reason: reason}
+	},
+}
+
+// Replace substitutes r for every undecodable byte and continues.
+func Replace(r rune) ErrorPolicy {
+	repl := []byte(string(r))
+	return ErrorPolicy{
+		name: "replace",
+		apply: func(offset int64, b byte, reason string) ([]byte, error) {
+			return repl, nil
+		},
+	}
+}
+
+// Skip drops undecodable bytes and continues.
+var Skip = ErrorPolicy{
+	name: "skip",
+	apply: func(offset int64, b byte, reason string) ([]byte, error) {
+		return nil, nil
+	},
+}
+
+// Escape replaces each undecodable byte with a "\xNN" escape and
+// continues.
+var Escape = ErrorPolicy{
+	name: "escape",
+	apply: func(offset int64, b byte, reason string) ([]byte, error) {
+		return []byte(fmt.Sprintf(`\x%02X`, b)), nil
+	},
+}
+
+// OnError sets the policy applied to bytes the Fixer cannot decode under
+// its assumed Charmap. The default is Strict.
+func OnError(p ErrorPolicy) func(*Fixer) error {
+	return func(f *Fixer) error {
+		f.errorPolicy = p
+		return nil
+	}
+}
+
+// OverlongPolicy controls what a Fixer does with an overlong UTF-8
+// sequence, i.e. one that encodes a code point using more bytes than
+// necessary.
+type OverlongPolicy int
+
+const (
+	// OverlongReject routes an overlong sequence through the Fixer's
+	// ErrorPolicy, as if its lead byte couldn't be decoded. This is the
+	// default.
+	OverlongReject OverlongPolicy = iota
+	// OverlongAccept passes an overlong sequence through unchanged.
+	OverlongAccept
+	// OverlongFold rewrites an overlong sequence to the shortest UTF-8
+	// encoding of the same code point, per Unicode Corrigendum #1.
+	OverlongFold
+)
+
+// OnOverlong sets the policy applied to overlong UTF-8 sequences. The
+// default is OverlongReject.
+func OnOverlong(p OverlongPolicy) func(*Fixer) error {
+	return func(f *Fixer) error {
+		f.overlongPolicy = p
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package fix_latin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestErrorPolicies(t *testing.T) {
+	// 0x80 is a raw Latin-1 control code point under ISO_8859_1, so
+	// without AllowControl it's undecodable and goes through the
+	// ErrorPolicy.
+	input := []byte("a\x80b")
+
+	cases := []struct {
+		name    string
+		policy  ErrorPolicy
+		want    string
+		wantErr bool
+	}{
+		{"strict", Strict, "", true},
+		{"replace", Replace('?'), "a?b", false},
+		{"skip", Skip, "ab", false},
+		{"escape", Escape, `a\x80b`, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := Fix(bytes.NewReader(input), &buf, Assume(ISO_8859_1), OnError(c.policy))
+			if c.wantErr {
+				var decodeErr *DecodeError
+				if !errors.As(err, &decodeErr) {
+					t.Fatalf("Fix with Strict: err = %v, want a *DecodeError", err)
+				}
+				if decodeErr.Byte != 0x80 {
+					t.Errorf("DecodeError.Byte = 0x%02X, want 0x80", decodeErr.Byte)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fix with %s: unexpected error: %v", c.name, err)
+			}
+			if got := buf.String(); got != c.want {
+				t.Errorf("Fix with %s = %q, want %q", c.name, got, c.want)
+			}
+		})
+	}
+}
+
+func TestAllowControlBypassesErrorPolicy(t *testing.T) {
+	input := []byte("a\x80b")
+
+	var buf bytes.Buffer
+	if _, err := Fix(bytes.NewReader(input), &buf, Assume(ISO_8859_1), AllowControl); err != nil {
+		t.Fatalf("Fix with AllowControl: unexpected error: %v", err)
+	}
+	want := "a" + string(rune(0x80)) + "b"
+	if got := buf.String(); got != want {
+		t.Errorf("Fix with AllowControl = %q, want %q", got, want)
+	}
+}
+
+func TestOverlongPolicies(t *testing.T) {
+	// C0 80 is an overlong 2-byte encoding of NUL (U+0000).
+	input := []byte{'a', 0xC0, 0x80, 'b'}
+
+	cases := []struct {
+		name    string
+		policy  OverlongPolicy
+		want    string
+		wantErr bool
+	}{
+		{"reject", OverlongReject, "", true},
+		{"accept", OverlongAccept, "a" + string([]byte{0xC0, 0x80}) + "b", false},
+		{"fold", OverlongFold, "a" + string(rune(0)) + "b", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := Fix(bytes.NewReader(input), &buf, Assume(ISO_8859_1), OnOverlong(c.policy))
+			if c.wantErr {
+				var decodeErr *DecodeError
+				if !errors.As(err, &decodeErr) {
+					t.Fatalf("Fix with OverlongReject: err = %v, want a *DecodeError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fix with %s: unexpected error: %v", c.name, err)
+			}
+			if got := buf.String(); got != c.want {
+				t.Errorf("Fix with %s = %q, want %q", c.name, got, c.want)
+			}
+		})
+	}
+}
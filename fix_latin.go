@@ -1,204 +1,259 @@
-package main
+// Package fix_latin repairs the mojibake that results from treating text
+// encoded in an 8-bit Windows or ISO encoding as if it were UTF-8: bytes
+// that already form well-formed UTF-8 are left alone, and stray bytes
+// outside it are reinterpreted under the assumed source Charmap.
+package fix_latin
 
 import (
 	"io"
-	"io/ioutil"
-	"os"
 	"unicode/utf8"
-)
-
-type Encoding int
 
-const (
-	ISO_8859_1 Encoding = iota
-	ISO_8859_15
-	CP1252
+	"golang.org/x/text/transform"
 )
 
+// Fixer is a transform.Transformer that rewrites a byte stream holding a
+// mix of valid UTF-8 and bytes from a single assumed 8-bit Charmap into
+// well-formed UTF-8. It is meant to be driven through transform.NewReader
+// or transform.NewWriter, e.g.:
+//
+//	f, _ := fix_latin.NewFixer(fix_latin.Assume(fix_latin.CP1252))
+//	r := transform.NewReader(src, f)
 type Fixer struct {
-	allowControl      bool
-	handleCP1252      bool
-	handleISO_8859_15 bool
-	// TODO: fix or reject overlong utf8 sequences
+	allowControl   bool
+	cm             *Charmap
+	auto           bool
+	errorPolicy    ErrorPolicy
+	overlongPolicy OverlongPolicy
+	replacements   int64
 }
 
-func AllowControl(f *Fixer) error {
-	f.allowControl = true
-	return nil
+// Replacements returns how many stray bytes the Fixer has reinterpreted
+// (or dropped/escaped under its ErrorPolicy), plus how many overlong or
+// invalid UTF-8 sequences it has folded or passed to its ErrorPolicy, so
+// far. Such a sequence counts once, however many bytes it spans.
+func (f *Fixer) Replacements() int64 {
+	return f.replacements
 }
 
-func Assume(e Encoding) func(*Fixer) error {
-	return func(f *Fixer) error {
-		switch e {
-		case CP1252:
-			f.handleCP1252 = true
-		case ISO_8859_15:
-			f.handleISO_8859_15 = true
+// NewFixer builds a Fixer from the given options. Its default
+// ErrorPolicy is Strict and its default OverlongPolicy is OverlongReject.
+func NewFixer(options ...func(*Fixer) error) (*Fixer, error) {
+	f := &Fixer{errorPolicy: Strict}
+	for _, option := range options {
+		if err := option(f); err != nil {
+			return nil, err
 		}
-		return nil
 	}
+	return f, nil
 }
 
-// remainder is ISO-8859-1
-var iso_8859_15 = map[byte][]byte{
-	0xA4: {0xE2, 0x82, 0xAC}, // EURO SIGN
-	0xA6: {0xC5, 0xA0},       // LATIN CAPITAL LETTER S WITH CARON
-	0xA8: {0xC5, 0xA1},       // LATIN SMALL LETTER S WITH CARON
-	0xB4: {0xC5, 0xBD},       // LATIN CAPITAL LETTER Z WITH CARON
-	0xB8: {0xC5, 0xBE},       // LATIN SMALL LETTER Z WITH CARON
-	0xBC: {0xC5, 0x92},       // LATIN CAPITAL LIGATURE OE
-	0xBD: {0xC5, 0x93},       // LATIN SMALL LIGATURE OE
-	0xBE: {0xC5, 0xB8},       // LATIN CAPITAL LETTER Y WITH DIAERESIS
-}
-
-// remainder is ISO-8859-1
-// does not define 0x81, 0x8D, 0x8F, 0x90, 09D
-var cp1252 = map[byte][]byte{
-	0x80: {0xE2, 0x82, 0xAC}, // EURO SIGN
-	0x82: {0xE2, 0x80, 0x9A}, // SINGLE LOW-9 QUOTATION MARK
-	0x83: {0xC6, 0x92},       // LATIN SMALL LETTER F WITH HOOK
-	0x84: {0xE2, 0x80, 0x9E}, // DOUBLE LOW-9 QUOTATION MARK
-	0x85: {0xE2, 0x80, 0xA6}, // HORIZONTAL ELLIPSIS
-	0x86: {0xE2, 0x80, 0xA0}, // DAGGER
-	0x87: {0xE2, 0x80, 0xA1}, // DOUBLE DAGGER
-	0x88: {0xCB, 0x86},       // MODIFIER LETTER CIRCUMFLEX ACCENT
-	0x89: {0xE2, 0x80, 0xB0}, // PER MILLE SIGN
-	0x8A: {0xC5, 0xA0},       // LATIN CAPITAL LETTER S WITH CARON
-	0x8B: {0xE2, 0x80, 0xB9}, // SINGLE LEFT-POINTING ANGLE QUOTATION MARK
-	0x8C: {0xC5, 0x92},       // LATIN CAPITAL LIGATURE OE
-	0x8E: {0xC5, 0xBD},       // LATIN CAPITAL LETTER Z WITH CARON
-	0x91: {0xE2, 0x80, 0x98}, // LEFT SINGLE QUOTATION MARK
-	0x92: {0xE2, 0x80, 0x99}, // RIGHT SINGLE QUOTATION MARK
-	0x93: {0xE2, 0x80, 0x9C}, // LEFT DOUBLE QUOTATION MARK
-	0x94: {0xE2, 0x80, 0x9D}, // RIGHT DOUBLE QUOTATION MARK
-	0x95: {0xE2, 0x80, 0xA2}, // BULLET
-	0x96: {0xE2, 0x80, 0x93}, // EN DASH
-	0x97: {0xE2, 0x80, 0x94}, // EM DASH
-	0x98: {0xCB, 0x9C},       // SMALL TILDE
-	0x99: {0xE2, 0x84, 0xA2}, // TRADE MARK SIGN
-	0x9A: {0xC5, 0xA1},       // LATIN SMALL LETTER S WITH CARON
-	0x9B: {0xE2, 0x80, 0xBA}, // SINGLE RIGHT-POINTING ANGLE QUOTATION MARK
-	0x9C: {0xC5, 0x93},       // LATIN SMALL LIGATURE OE
-	0x9E: {0xC5, 0xBE},       // LATIN SMALL LETTER Z WITH CARON
-	0x9F: {0xC5, 0xB8},       // LATIN CAPITAL LETTER Y WITH DIAERESIS
+// AllowControl stops the Fixer from rejecting the Latin-1 high-order
+// control characters (0x80-0x9F) that most mis-encoded text doesn't
+// actually contain.
+func AllowControl(f *Fixer) error {
+	f.allowControl = true
+	return nil
 }
 
-func Fix(r io.Reader, w io.Writer, options ...func(*Fixer) error) {
-	f := &Fixer{}
-
-	for _, option := range options {
-		err := option(f)
-		if err != nil {
-			panic("invalid option")
-		}
+// Assume selects the Charmap that stray bytes are reinterpreted under.
+// Assume(Auto) defers the choice to Detect; it only has an effect when the
+// Fixer is driven through Fix, which runs Detect against the input before
+// the first Transform call.
+func Assume(cm *Charmap) func(*Fixer) error {
+	return func(f *Fixer) error {
+		f.cm = cm
+		f.auto = cm == Auto
+		return nil
 	}
+}
 
-	input, err := ioutil.ReadAll(r)
-	if err != nil {
-		panic(err)
-	}
-	output := make([]byte, 0)
+// Reset implements transform.Transformer.
+func (f *Fixer) Reset() {}
 
-	for len(input) > 0 {
-		// UTF-8 "self" / ASCII
-		if input[0] < utf8.RuneSelf {
-			output = append(output, input[0])
-			input = input[1:]
-			continue
-		}
+// Transform implements transform.Transformer. It copies runs of
+// already-valid UTF-8 from src to dst unchanged, and reinterprets every
+// other stray byte under the Fixer's assumed Charmap. A sequence shaped
+// like UTF-8 but overlong is handled per the Fixer's OverlongPolicy, and
+// one encoding a surrogate half or a code point above U+10FFFF goes to
+// its ErrorPolicy instead of being reinterpreted. Partial multibyte
+// sequences at the end of src are reported as transform.ErrShortSrc unless
+// atEOF, and a dst that's too small to hold the next decoded character is
+// reported as transform.ErrShortDst.
+func (f *Fixer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		b0 := src[nSrc]
 
-		// UTF-8 size 2
-		if input[0] >= 0xC0 && input[0] <= 0xDF &&
-			input[1] >= 0x80 && input[1] <= 0xBF {
-			output = append(output, input[0])
-			output = append(output, input[1])
-			input = input[2:]
-			continue
-		}
-
-		// UTF-8 size 3
-		if input[0] >= 0xE0 && input[0] <= 0xEF &&
-			input[1] >= 0x80 && input[1] <= 0xBF &&
-			input[2] >= 0x80 && input[2] <= 0xBF {
-			output = append(output, input[0])
-			output = append(output, input[1])
-			output = append(output, input[2])
-			input = input[3:]
+		if b0 < utf8.RuneSelf {
+			if nDst >= len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = b0
+			nDst++
+			nSrc++
 			continue
 		}
 
-		// UTF-8 size 4
-		if input[0] >= 0xF0 && input[0] <= 0xF7 &&
-			input[1] >= 0x80 && input[1] <= 0xBF &&
-			input[2] >= 0x80 && input[2] <= 0xBF &&
-			input[3] >= 0x80 && input[3] <= 0xBF {
-			output = append(output, input[0])
-			output = append(output, input[1])
-			output = append(output, input[2])
-			output = append(output, input[3])
-			input = input[4:]
-			continue
-		}
+		if size := utf8LeadLen(b0); size > 1 {
+			if nSrc+size > len(src) {
+				if !atEOF {
+					return nDst, nSrc, transform.ErrShortSrc
+				}
+			} else if isValidUTF8Seq(src[nSrc : nSrc+size]) {
+				seq := src[nSrc : nSrc+size]
+				out := seq
 
-		// UTF-8 size 5
-		if input[0] >= 0xF8 && input[0] <= 0xFB &&
-			input[1] >= 0x80 && input[1] <= 0xBF &&
-			input[2] >= 0x80 && input[2] <= 0xBF &&
-			input[3] >= 0x80 && input[3] <= 0xBF &&
-			input[4] >= 0x80 && input[4] <= 0xBF {
-			output = append(output, input[0])
-			output = append(output, input[1])
-			output = append(output, input[2])
-			output = append(output, input[3])
-			output = append(output, input[4])
-			input = input[5:]
-			continue
-		}
+				switch cp := decodeCodepoint(seq); {
+				case cp < utf8MinCodepoint[size]:
+					// Overlong: shaped like UTF-8, but not the shortest
+					// encoding of cp. Handled by OverlongPolicy rather
+					// than the strict DFA below, which would otherwise
+					// always reject it.
+					switch f.overlongPolicy {
+					case OverlongFold:
+						out = []byte(string(cp))
+						f.replacements++
+					case OverlongAccept:
+						// out already holds the original overlong seq
+					default: // OverlongReject
+						repl, err := f.errorPolicy.apply(int64(nSrc), b0, "overlong utf8 sequence")
+						if err != nil {
+							return nDst, nSrc, err
+						}
+						if nDst+len(repl) > len(dst) {
+							return nDst, nSrc, transform.ErrShortDst
+						}
+						copy(dst[nDst:], repl)
+						nDst += len(repl)
+						nSrc += size
+						f.replacements++
+						continue
+					}
+				case !isStrictUTF8Seq(seq):
+					// Not overlong, but still invalid per RFC 3629: a
+					// surrogate half (U+D800-U+DFFF) or a code point
+					// above U+10FFFF.
+					repl, err := f.errorPolicy.apply(int64(nSrc), b0, "invalid utf8 sequence")
+					if err != nil {
+						return nDst, nSrc, err
+					}
+					if nDst+len(repl) > len(dst) {
+						return nDst, nSrc, transform.ErrShortDst
+					}
+					copy(dst[nDst:], repl)
+					nDst += len(repl)
+					nSrc += size
+					f.replacements++
+					continue
+				}
 
-		// CP1252
-		if f.handleCP1252 {
-			if bytes, ok := cp1252[input[0]]; ok {
-				for _, b := range bytes {
-					output = append(output, b)
+				if nDst+len(out) > len(dst) {
+					return nDst, nSrc, transform.ErrShortDst
 				}
-				input = input[1:]
+				copy(dst[nDst:], out)
+				nDst += len(out)
+				nSrc += size
 				continue
 			}
 		}
 
-		// ISO-8859-15
-		if f.handleISO_8859_15 {
-			if bytes, ok := iso_8859_15[input[0]]; ok {
-				for _, b := range bytes {
-					output = append(output, b)
-				}
-				input = input[1:]
-				continue
+		repl, ok := f.decodeByte(b0)
+		if !ok {
+			repl, err = f.errorPolicy.apply(int64(nSrc), b0, "undecodable byte")
+			if err != nil {
+				return nDst, nSrc, err
 			}
 		}
-
-		// ISO-8859-1 high-order control chars
-		if !f.allowControl && input[0] >= 0x80 && input[0] <= 0x9F {
-			panic("control char")
-			continue
+		if nDst+len(repl) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
 		}
+		copy(dst[nDst:], repl)
+		nDst += len(repl)
+		nSrc++
+		f.replacements++
+	}
+	return nDst, nSrc, nil
+}
 
-		// ISO-8859-1
-		if input[0] >= 0x80 && input[0] <= 0xFF {
-			bytes := []byte(string(rune(input[0])))
-			for _, b := range bytes {
-				output = append(output, b)
-			}
-			input = input[1:]
-			continue
-		}
+// utf8MinCodepoint is the smallest code point that legitimately requires
+// an N-byte UTF-8 sequence; anything smaller reached via an N-byte
+// sequence is overlong.
+var utf8MinCodepoint = map[int]rune{2: 0x80, 3: 0x800, 4: 0x10000}
 
-		panic("unhandled char")
+// decodeCodepoint reassembles the code point encoded by a well-formed
+// (per isValidUTF8Seq) 2-, 3- or 4-byte UTF-8 sequence, without checking
+// it for overlong-ness.
+func decodeCodepoint(seq []byte) rune {
+	switch len(seq) {
+	case 2:
+		return rune(seq[0]&0x1F)<<6 | rune(seq[1]&0x3F)
+	case 3:
+		return rune(seq[0]&0x0F)<<12 | rune(seq[1]&0x3F)<<6 | rune(seq[2]&0x3F)
+	default:
+		return rune(seq[0]&0x07)<<18 | rune(seq[1]&0x3F)<<12 | rune(seq[2]&0x3F)<<6 | rune(seq[3]&0x3F)
+	}
+}
+
+// decodeByte reinterprets a single byte that isn't part of valid UTF-8
+// under the Fixer's assumed Charmap, returning its UTF-8 representation.
+func (f *Fixer) decodeByte(b byte) ([]byte, bool) {
+	cm := f.cm
+	if cm == nil {
+		cm = ISO_8859_1
 	}
 
-	w.Write(output)
+	if b >= 0x80 && b <= 0x9F && !f.allowControl && cm.isRawControl(b) {
+		return nil, false
+	}
+
+	return cm.Decode(b)
+}
+
+// utf8LeadLen returns the length in bytes of the UTF-8 sequence that b0
+// would start, or 1 if b0 isn't a recognised multibyte lead byte. RFC
+// 3629 caps UTF-8 at 4 bytes, so a former lead byte of 0xF8-0xFB is no
+// longer treated as one: it falls through to decodeByte like any other
+// stray byte.
+func utf8LeadLen(b0 byte) int {
+	switch {
+	case b0 >= 0xC0 && b0 <= 0xDF:
+		return 2
+	case b0 >= 0xE0 && b0 <= 0xEF:
+		return 3
+	case b0 >= 0xF0 && b0 <= 0xF7:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// isValidUTF8Seq reports whether seq's continuation bytes all fall in the
+// 0x80-0xBF range expected of a UTF-8 continuation byte.
+func isValidUTF8Seq(seq []byte) bool {
+	for _, b := range seq[1:] {
+		if b < 0x80 || b > 0xBF {
+			return false
+		}
+	}
+	return true
 }
 
-func main() {
-	Fix(os.Stdin, os.Stdout, AllowControl, Assume(CP1252))
+// Fix reads r, fixing up mis-encoded bytes per the given options, writes
+// the result to w, and reports the number of bytes written.
+func Fix(r io.Reader, w io.Writer, options ...func(*Fixer) error) (written int64, err error) {
+	f, err := NewFixer(options...)
+	if err != nil {
+		return 0, err
+	}
+
+	if f.auto {
+		result, rest, err := Detect(r)
+		if err != nil {
+			return 0, err
+		}
+		r = rest
+		f.cm = result.Charmap
+		f.auto = false
+	}
+
+	return io.Copy(w, transform.NewReader(r, f))
 }
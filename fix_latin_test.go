@@ -0,0 +1,72 @@
+package fix_latin
+
+import (
+	"testing"
+
+	"golang.org/x/text/transform"
+)
+
+// TestTransformShortSrcAtSequenceBoundary checks that Transform reports
+// ErrShortSrc, rather than misreading a partial multibyte sequence, when
+// src ends mid-sequence and atEOF is false.
+func TestTransformShortSrcAtSequenceBoundary(t *testing.T) {
+	f, err := NewFixer(Assume(CP1252))
+	if err != nil {
+		t.Fatalf("NewFixer: %v", err)
+	}
+
+	src := []byte{0xC3} // lead byte of a 2-byte UTF-8 sequence ("é"), no continuation byte yet
+	dst := make([]byte, 16)
+
+	nDst, nSrc, err := f.Transform(dst, src, false)
+	if err != transform.ErrShortSrc {
+		t.Fatalf("Transform(%v, atEOF=false) err = %v, want ErrShortSrc", src, err)
+	}
+	if nDst != 0 || nSrc != 0 {
+		t.Errorf("Transform(%v, atEOF=false) = (%d, %d), want (0, 0)", src, nDst, nSrc)
+	}
+}
+
+// TestTransformShortDstOnValidUTF8 checks that Transform reports
+// ErrShortDst, without writing a partial character, when dst is too
+// small to hold a passed-through multibyte UTF-8 sequence.
+func TestTransformShortDstOnValidUTF8(t *testing.T) {
+	f, err := NewFixer(Assume(CP1252))
+	if err != nil {
+		t.Fatalf("NewFixer: %v", err)
+	}
+
+	src := []byte{0xC3, 0xA9} // "é", valid UTF-8
+	dst := make([]byte, 1)    // too small to hold both bytes
+
+	nDst, nSrc, err := f.Transform(dst, src, true)
+	if err != transform.ErrShortDst {
+		t.Fatalf("Transform(%v, atEOF=true) err = %v, want ErrShortDst", src, err)
+	}
+	if nDst != 0 || nSrc != 0 {
+		t.Errorf("Transform(%v, atEOF=true) = (%d, %d), want (0, 0)", src, nDst, nSrc)
+	}
+}
+
+// TestTransformShortDstOnReinterpretedByte checks the same ErrShortDst
+// boundary behaviour for a stray byte reinterpreted under the assumed
+// Charmap (as opposed to a passed-through valid UTF-8 sequence): CP1252's
+// euro sign decodes to a 3-byte UTF-8 sequence, which shouldn't fit in a
+// 2-byte dst.
+func TestTransformShortDstOnReinterpretedByte(t *testing.T) {
+	f, err := NewFixer(Assume(CP1252), AllowControl)
+	if err != nil {
+		t.Fatalf("NewFixer: %v", err)
+	}
+
+	src := []byte{0x80} // CP1252 EURO SIGN, decodes to 3 UTF-8 bytes
+	dst := make([]byte, 2)
+
+	nDst, nSrc, err := f.Transform(dst, src, true)
+	if err != transform.ErrShortDst {
+		t.Fatalf("Transform(%v, atEOF=true) err = %v, want ErrShortDst", src, err)
+	}
+	if nDst != 0 || nSrc != 0 {
+		t.Errorf("Transform(%v, atEOF=true) = (%d, %d), want (0, 0)", src, nDst, nSrc)
+	}
+}
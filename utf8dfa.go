@@ -0,0 +1,56 @@
+package fix_latin
+
+// utf8Accept and utf8Reject are the two terminal states of the UTF-8
+// validation DFA below; every other reachable state is a mid-sequence
+// state waiting on more continuation bytes.
+const (
+	utf8Accept = 0
+	utf8Reject = 12
+)
+
+// utf8dfa is Björn Höhrmann's UTF-8 decoding DFA
+// (https://bjoern.hoehrmann.de/utf-8/decoder/dfa/). The first 256 bytes
+// classify every possible byte value into one of 12 character classes;
+// the remaining 108 bytes are a transition table indexed by
+// state+class, mapping a (state, byte class) pair to the next state.
+// This rejects overlong encodings, UTF-16 surrogate halves and code
+// points above U+10FFFF as a side effect of how the lead byte and first
+// continuation byte are classified, which a simple byte-range check
+// cannot do.
+var utf8dfa = [364]byte{
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9,
+	7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7,
+	8, 8, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
+	10, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 4, 3, 3, 11, 6, 6, 6, 5, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8,
+
+	0, 12, 24, 36, 60, 96, 84, 12, 12, 12, 48, 72, // state 0
+	12, 12, 12, 12, 12, 12, 12, 12, 12, 12, 12, 12, // state 12 (REJECT)
+	12, 0, 12, 12, 12, 12, 12, 0, 12, 0, 12, 12, // state 24
+	12, 24, 12, 12, 12, 12, 12, 24, 12, 24, 12, 12, // state 36
+	12, 12, 12, 12, 12, 12, 12, 24, 12, 12, 12, 12, // state 48
+	12, 24, 12, 12, 12, 12, 12, 12, 12, 24, 12, 12, // state 60
+	12, 12, 12, 12, 12, 12, 12, 36, 12, 36, 12, 12, // state 72
+	12, 36, 12, 12, 12, 12, 12, 36, 12, 36, 12, 12, // state 84
+	12, 36, 12, 12, 12, 12, 12, 12, 12, 12, 12, 12, // state 96
+}
+
+// utf8DFAStep feeds one byte through the DFA, returning the next state.
+func utf8DFAStep(state uint32, b byte) uint32 {
+	class := uint32(utf8dfa[b])
+	return uint32(utf8dfa[256+state+class])
+}
+
+// isStrictUTF8Seq reports whether seq is a single complete, well-formed
+// UTF-8 sequence per RFC 3629: no overlong encoding, no UTF-16 surrogate
+// half, and no code point above U+10FFFF.
+func isStrictUTF8Seq(seq []byte) bool {
+	state := uint32(utf8Accept)
+	for _, b := range seq {
+		state = utf8DFAStep(state, b)
+	}
+	return state == utf8Accept
+}
@@ -0,0 +1,60 @@
+package fix_latin
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIsStrictUTF8Seq(t *testing.T) {
+	cases := []struct {
+		name string
+		seq  []byte
+		want bool
+	}{
+		{"2-byte lower bound", []byte{0xC2, 0x80}, true},
+		{"2-byte upper bound", []byte{0xDF, 0xBF}, true},
+		{"2-byte overlong (C0 80)", []byte{0xC0, 0x80}, false},
+		{"3-byte E0 lower bound", []byte{0xE0, 0xA0, 0x80}, true},
+		{"3-byte E0 overlong", []byte{0xE0, 0x80, 0x80}, false},
+		{"3-byte generic E1-EC (EURO SIGN)", []byte{0xE2, 0x82, 0xAC}, true},
+		{"3-byte ED just below surrogates", []byte{0xED, 0x9F, 0xBF}, true},
+		{"3-byte ED first surrogate (U+D800)", []byte{0xED, 0xA0, 0x80}, false},
+		{"3-byte ED last surrogate (U+DFFF)", []byte{0xED, 0xBF, 0xBF}, false},
+		{"3-byte EE/EF", []byte{0xEE, 0x80, 0x80}, true},
+		{"4-byte F0 lower bound (U+10000)", []byte{0xF0, 0x90, 0x80, 0x80}, true},
+		{"4-byte F0 overlong", []byte{0xF0, 0x80, 0x80, 0x80}, false},
+		{"4-byte F0 mid-range, U+1F600 GRINNING FACE", []byte{0xF0, 0x9F, 0x98, 0x80}, true},
+		{"4-byte F0 upper, U+20000", []byte{0xF0, 0xA0, 0x80, 0x80}, true},
+		{"4-byte F0 at top of range, U+3FFFF", []byte{0xF0, 0xBF, 0xBF, 0xBF}, true},
+		{"4-byte F1-F3 generic", []byte{0xF1, 0x80, 0x80, 0x80}, true},
+		{"4-byte F4 at U+10FFFF", []byte{0xF4, 0x8F, 0xBF, 0xBF}, true},
+		{"4-byte F4 beyond U+10FFFF", []byte{0xF4, 0x90, 0x80, 0x80}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isStrictUTF8Seq(c.seq); got != c.want {
+				t.Errorf("isStrictUTF8Seq(% X) = %v, want %v", c.seq, got, c.want)
+			}
+		})
+	}
+}
+
+// TestFixLeavesOrdinaryEmojiAlone guards against the state-72 transition
+// bug where every F0-led sequence (U+10000-U+3FFFF, most emoji and CJK
+// Extension B) was rejected outright.
+func TestFixLeavesOrdinaryEmojiAlone(t *testing.T) {
+	cases := []string{
+		"\U0001F600",  // 😀 GRINNING FACE, U+1F600
+		"\U0001F000",  // 🀀 MAHJONG TILE EAST WIND, U+1F000
+		"\U00020000",  // U+20000, CJK Extension B
+	}
+	for _, want := range cases {
+		var buf bytes.Buffer
+		if _, err := Fix(bytes.NewReader([]byte(want)), &buf, Assume(CP1252)); err != nil {
+			t.Fatalf("Fix(%q) returned error: %v", want, err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("Fix(%q) = %q, want it unchanged", want, got)
+		}
+	}
+}